@@ -0,0 +1,114 @@
+package remote
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Verifier validates credentials on an incoming request, rejecting it if they don't check
+// out. It is the server-side counterpart of Auth: BearerVerifier and HMACVerifier check what
+// BearerAuth and HMACAuth sign.
+type Verifier interface {
+	Verify(req *http.Request, body []byte) error
+}
+
+// BearerVerifier checks the Authorization: Bearer <token> header set by BearerAuth. Valid
+// reports whether token is currently accepted, e.g. by looking it up in a token store.
+type BearerVerifier struct {
+	Valid func(token string) bool
+}
+
+// Verify checks the bearer token on req.
+func (v BearerVerifier) Verify(req *http.Request, body []byte) error {
+	const prefix = "Bearer "
+	h := req.Header.Get("Authorization")
+	if !strings.HasPrefix(h, prefix) {
+		return errors.New("missing bearer token")
+	}
+	if !v.Valid(strings.TrimPrefix(h, prefix)) {
+		return errors.New("invalid bearer token")
+	}
+	return nil
+}
+
+// defaultHMACMaxSkew bounds how far a request's timestamp may drift from now before HMACVerifier
+// rejects it as a replay.
+const defaultHMACMaxSkew = 5 * time.Minute
+
+// HMACVerifier checks the X-Remark-Timestamp/X-Remark-Signature headers set by HMACAuth.
+// Secret looks up the shared secret for a key ID, returning ok=false if it is unknown.
+type HMACVerifier struct {
+	Secret  func(keyID string) (secret []byte, ok bool)
+	MaxSkew time.Duration // defaults to defaultHMACMaxSkew
+}
+
+// Verify recomputes the HMAC over body and the request's timestamp and compares it against
+// the signature header, rejecting unknown key IDs and timestamps outside MaxSkew of now.
+func (v HMACVerifier) Verify(req *http.Request, body []byte) error {
+	ts := req.Header.Get(hmacTimestampHeader)
+	sig := req.Header.Get(hmacSignatureHeader)
+	if ts == "" || sig == "" {
+		return errors.New("missing hmac signature")
+	}
+
+	parts := strings.SplitN(sig, ":", 2)
+	if len(parts) != 2 {
+		return errors.New("malformed hmac signature")
+	}
+	keyID, sum := parts[0], parts[1]
+
+	secret, ok := v.Secret(keyID)
+	if !ok {
+		return errors.Errorf("unknown hmac key %q", keyID)
+	}
+
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return errors.Wrap(err, "bad hmac timestamp")
+	}
+	skew := v.MaxSkew
+	if skew <= 0 {
+		skew = defaultHMACMaxSkew
+	}
+	if age := time.Since(time.Unix(sec, 0)); age < -skew || age > skew {
+		return errors.Errorf("hmac timestamp outside allowed skew of %s", skew)
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)       // nolint errcheck
+	mac.Write([]byte(ts)) // nolint errcheck
+	expected := hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(sum), []byte(expected)) {
+		return errors.New("hmac signature mismatch")
+	}
+	return nil
+}
+
+// AuthMiddleware wraps next, rejecting with 401 Unauthorized any request that fails v.Verify
+// before it reaches next. It lets a remote-engine server validate the same Bearer/HMAC schemes
+// BearerAuth and HMACAuth sign on the client.
+func AuthMiddleware(v Verifier, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "failed to read request body", http.StatusBadRequest)
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		if err := v.Verify(r, body); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
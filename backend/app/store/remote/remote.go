@@ -0,0 +1,353 @@
+// Package remote implements engine.Interface by calling a remote http server.
+// It talks JSON-RPC 2.0: {"jsonrpc":"2.0","method":...,"params":[...],"id":...}
+// request and {"jsonrpc":"2.0","result":...,"id":...} / {..., "error":{"code","message","data"}}
+// response, with Client.Legacy falling back to the older bare {method,params}/{result,error} shape.
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+
+	"github.com/umputun/remark/backend/app/store"
+	"github.com/umputun/remark/backend/app/store/engine"
+)
+
+// Client implements engine.Interface talking to remote engine over http.
+// By default it speaks JSON-RPC 2.0; set Legacy to talk to backends
+// predating the jsonrpc/id/structured-error upgrade.
+type Client struct {
+	API    string
+	Client http.Client
+	Legacy bool
+	Auth   Auth        // optional, signs each outgoing request; see auth.go
+	Retry  RetryConfig // backoff/retry behavior for transient failures; see retry.go
+
+	id uint64 // atomic request id counter, JSON-RPC 2.0 mode only
+}
+
+// RPCError is a JSON-RPC 2.0 error object, letting callers distinguish
+// application-level failures (e.g. "not found") from transport failures.
+type RPCError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string { return e.Message }
+
+type rpcRequest struct {
+	JSONRPC string        `json:"jsonrpc"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+	ID      uint64        `json:"id"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result"`
+	Error   *RPCError       `json:"error,omitempty"`
+	ID      uint64          `json:"id"`
+}
+
+// Call is a single method/params pair for a batched JSON-RPC 2.0 request.
+type Call struct {
+	Method string
+	Params []interface{}
+}
+
+// Result is a single response within a Batch call, correlated with its Call by position.
+type Result struct {
+	Result json.RawMessage
+	Error  *RPCError
+}
+
+// Create comment and return ID
+func (r *Client) Create(comment store.Comment) (commentID string, err error) {
+	return r.CreateCtx(context.Background(), comment)
+}
+
+// CreateCtx comment and return ID, aborting the call if ctx is canceled
+func (r *Client) CreateCtx(ctx context.Context, comment store.Comment) (commentID string, err error) {
+	var result string
+	err = r.callCtx(ctx, "create", []interface{}{comment}, &result, true)
+	return result, err
+}
+
+// Get comment by locator and commentID
+func (r *Client) Get(locator store.Locator, commentID string) (comment store.Comment, err error) {
+	return r.GetCtx(context.Background(), locator, commentID)
+}
+
+// GetCtx comment by locator and commentID, aborting the call if ctx is canceled
+func (r *Client) GetCtx(ctx context.Context, locator store.Locator, commentID string) (comment store.Comment, err error) {
+	err = r.callCtx(ctx, "get", []interface{}{locator, commentID}, &comment, false)
+	return comment, err
+}
+
+// Update comment, mutable parts only
+func (r *Client) Update(locator store.Locator, comment store.Comment) error {
+	return r.UpdateCtx(context.Background(), locator, comment)
+}
+
+// UpdateCtx comment, mutable parts only, aborting the call if ctx is canceled
+func (r *Client) UpdateCtx(ctx context.Context, locator store.Locator, comment store.Comment) error {
+	return r.callCtx(ctx, "update", []interface{}{locator, comment}, nil, true)
+}
+
+// Find comments for the given request
+func (r *Client) Find(req engine.FindRequest) (comments []store.Comment, err error) {
+	return r.FindCtx(context.Background(), req)
+}
+
+// FindCtx comments for the given request, aborting the call if ctx is canceled
+func (r *Client) FindCtx(ctx context.Context, req engine.FindRequest) (comments []store.Comment, err error) {
+	err = r.callCtx(ctx, "find", []interface{}{req}, &comments, false)
+	return comments, err
+}
+
+// Info returns post(s) meta info
+func (r *Client) Info(req engine.InfoRequest) (info []store.PostInfo, err error) {
+	return r.InfoCtx(context.Background(), req)
+}
+
+// InfoCtx returns post(s) meta info, aborting the call if ctx is canceled
+func (r *Client) InfoCtx(ctx context.Context, req engine.InfoRequest) (info []store.PostInfo, err error) {
+	err = r.callCtx(ctx, "info", []interface{}{req}, &info, false)
+	return info, err
+}
+
+// Flag sets, resets or checks flag
+func (r *Client) Flag(req engine.FlagRequest) (val bool, err error) {
+	return r.FlagCtx(context.Background(), req)
+}
+
+// FlagCtx sets, resets or checks flag, aborting the call if ctx is canceled
+func (r *Client) FlagCtx(ctx context.Context, req engine.FlagRequest) (val bool, err error) {
+	err = r.callCtx(ctx, "flag", []interface{}{req}, &val, true)
+	return val, err
+}
+
+// ListFlags get list of flagged keys, like blocked & verified user
+func (r *Client) ListFlags(siteID string, flag engine.Flag) (res []interface{}, err error) {
+	return r.ListFlagsCtx(context.Background(), siteID, flag)
+}
+
+// ListFlagsCtx get list of flagged keys, aborting the call if ctx is canceled
+func (r *Client) ListFlagsCtx(ctx context.Context, siteID string, flag engine.Flag) (res []interface{}, err error) {
+	err = r.callCtx(ctx, "list_flags", []interface{}{siteID, flag}, &res, false)
+	return res, err
+}
+
+// Count gets comments count for post or user
+func (r *Client) Count(req engine.FindRequest) (count int, err error) {
+	return r.CountCtx(context.Background(), req)
+}
+
+// CountCtx gets comments count for post or user, aborting the call if ctx is canceled
+func (r *Client) CountCtx(ctx context.Context, req engine.FindRequest) (count int, err error) {
+	err = r.callCtx(ctx, "count", []interface{}{req}, &count, false)
+	return count, err
+}
+
+// Delete post(s), comment(s) or user
+func (r *Client) Delete(req engine.DeleteRequest) error {
+	return r.DeleteCtx(context.Background(), req)
+}
+
+// DeleteCtx post(s), comment(s) or user, aborting the call if ctx is canceled
+func (r *Client) DeleteCtx(ctx context.Context, req engine.DeleteRequest) error {
+	return r.callCtx(ctx, "delete", []interface{}{req}, nil, true)
+}
+
+// Close the remote engine
+func (r *Client) Close() error {
+	return r.CloseCtx(context.Background())
+}
+
+// CloseCtx the remote engine, aborting the call if ctx is canceled
+func (r *Client) CloseCtx(ctx context.Context) error {
+	return r.callCtx(ctx, "close", nil, nil, false)
+}
+
+// writeMethods are the JSON-RPC methods that mutate state, i.e. the ones Create/Update/
+// Delete/Flag call with isWrite=true; Batch rejects them since a retried batch can't carry
+// a per-call Idempotency-Key.
+var writeMethods = map[string]bool{"create": true, "update": true, "delete": true, "flag": true}
+
+func isWriteMethod(method string) bool {
+	return writeMethods[method]
+}
+
+// callCtx makes the actual http request for method/params and decodes the result into result,
+// honoring ctx deadline and cancellation for both the in-flight request and any retry backoff.
+// isWrite marks Create/Update/Delete/Flag, which must only retry when the request is known
+// not to have been accepted by the server; see doWithRetry.
+func (r *Client) callCtx(ctx context.Context, method string, params []interface{}, result interface{}, isWrite bool) error {
+	if r.Legacy {
+		return r.callLegacy(ctx, method, params, result, isWrite)
+	}
+
+	reqBody := rpcRequest{JSONRPC: "2.0", Method: method, Params: params, ID: atomic.AddUint64(&r.id, 1)}
+
+	body, err := json.Marshal(reqBody)
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal request for %s", method)
+	}
+
+	resp, err := r.doWithRetry(ctx, method, body, isWrite)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() // nolint gas
+
+	jsResp := rpcResponse{}
+	if err = json.NewDecoder(resp.Body).Decode(&jsResp); err != nil {
+		return errors.Wrapf(err, "failed to decode response for %s", method)
+	}
+
+	if jsResp.Error != nil {
+		return jsResp.Error
+	}
+
+	if result == nil || jsResp.Result == nil {
+		return nil
+	}
+	return json.Unmarshal(jsResp.Result, result)
+}
+
+// callLegacy speaks the pre-JSON-RPC-2.0 wire format: a bare {"method":...,"params":[...]}
+// request and a {"result":...} / {"error":"msg"} response, with no version tag, id or
+// structured error. Kept for talking to backends that predate the upgrade, via Client.Legacy.
+func (r *Client) callLegacy(ctx context.Context, method string, params []interface{}, result interface{}, isWrite bool) error {
+	reqBody := struct {
+		Method string        `json:"method"`
+		Params []interface{} `json:"params"`
+	}{Method: method, Params: params}
+
+	body, err := json.Marshal(reqBody)
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal request for %s", method)
+	}
+
+	resp, err := r.doWithRetry(ctx, method, body, isWrite)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() // nolint gas
+
+	jsResp := struct {
+		Result json.RawMessage `json:"result"`
+		Error  string          `json:"error"`
+	}{}
+
+	if err = json.NewDecoder(resp.Body).Decode(&jsResp); err != nil {
+		return errors.Wrapf(err, "failed to decode response for %s", method)
+	}
+
+	if jsResp.Error != "" {
+		return errors.New(jsResp.Error)
+	}
+
+	if result == nil || jsResp.Result == nil {
+		return nil
+	}
+	return json.Unmarshal(jsResp.Result, result)
+}
+
+// Batch sends calls as a single JSON-RPC 2.0 batch request in one http round trip and
+// returns results in the same order as calls, correlated with the response by id.
+// Not available when Client.Legacy is set, as the legacy wire format has no batch support.
+func (r *Client) Batch(ctx context.Context, calls []Call) ([]Result, error) {
+	if r.Legacy {
+		return nil, errors.New("batch calls are not supported in legacy mode")
+	}
+	if len(calls) == 0 {
+		return nil, nil
+	}
+	for _, c := range calls {
+		if isWriteMethod(c.Method) {
+			return nil, errors.Errorf("batch calls do not support write method %q", c.Method)
+		}
+	}
+
+	reqs := make([]rpcRequest, len(calls))
+	ids := make([]uint64, len(calls))
+	for i, c := range calls {
+		id := atomic.AddUint64(&r.id, 1)
+		ids[i] = id
+		reqs[i] = rpcRequest{JSONRPC: "2.0", Method: c.Method, Params: c.Params, ID: id}
+	}
+
+	body, err := json.Marshal(reqs)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal batch request")
+	}
+
+	resp, err := r.doWithRetry(ctx, "batch", body, false)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() // nolint gas
+
+	var rpcResps []rpcResponse
+	if err = json.NewDecoder(resp.Body).Decode(&rpcResps); err != nil {
+		return nil, errors.Wrap(err, "failed to decode response for batch")
+	}
+
+	byID := make(map[uint64]rpcResponse, len(rpcResps))
+	for _, rr := range rpcResps {
+		byID[rr.ID] = rr
+	}
+
+	results := make([]Result, len(calls))
+	for i, id := range ids {
+		rr, ok := byID[id]
+		if !ok {
+			return nil, errors.Errorf("no response for batched call %q (id %d)", calls[i].Method, id)
+		}
+		results[i] = Result{Result: rr.Result, Error: rr.Error}
+	}
+	return results, nil
+}
+
+// FindCountInfo batches Find, Count and Info in a single round trip, the combination
+// needed to render a site's comment list.
+func (r *Client) FindCountInfo(ctx context.Context, findReq engine.FindRequest, countReq engine.FindRequest,
+	infoReq engine.InfoRequest) (comments []store.Comment, count int, info []store.PostInfo, err error) {
+
+	results, err := r.Batch(ctx, []Call{
+		{Method: "find", Params: []interface{}{findReq}},
+		{Method: "count", Params: []interface{}{countReq}},
+		{Method: "info", Params: []interface{}{infoReq}},
+	})
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	if results[0].Error != nil {
+		return nil, 0, nil, errors.Wrap(results[0].Error, "find failed")
+	}
+	if err = json.Unmarshal(results[0].Result, &comments); err != nil {
+		return nil, 0, nil, errors.Wrap(err, "failed to decode find result")
+	}
+
+	if results[1].Error != nil {
+		return nil, 0, nil, errors.Wrap(results[1].Error, "count failed")
+	}
+	if err = json.Unmarshal(results[1].Result, &count); err != nil {
+		return nil, 0, nil, errors.Wrap(err, "failed to decode count result")
+	}
+
+	if results[2].Error != nil {
+		return nil, 0, nil, errors.Wrap(results[2].Error, "info failed")
+	}
+	if err = json.Unmarshal(results[2].Result, &info); err != nil {
+		return nil, 0, nil, errors.Wrap(err, "failed to decode info result")
+	}
+
+	return comments, count, info, nil
+}
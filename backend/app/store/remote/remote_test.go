@@ -1,11 +1,22 @@
 package remote
 
 import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -17,7 +28,7 @@ import (
 )
 
 func TestClient_Create(t *testing.T) {
-	ts := testServer(t, `{"method":"create","params":[{"id":"123","pid":"","text":"msg","user":{"name":"","id":"","picture":"","admin":false},"locator":{"site":"site","url":"http://example.com/url"},"score":0,"vote":0,"time":"0001-01-01T00:00:00Z"}]}`, `{"result":"12345"}`)
+	ts := testServer(t, `{"jsonrpc":"2.0","method":"create","params":[{"id":"123","pid":"","text":"msg","user":{"name":"","id":"","picture":"","admin":false},"locator":{"site":"site","url":"http://example.com/url"},"score":0,"vote":0,"time":"0001-01-01T00:00:00Z"}],"id":1}`, `{"result":"12345"}`)
 	defer ts.Close()
 	c := Client{API: ts.URL, Client: http.Client{}}
 
@@ -29,7 +40,7 @@ func TestClient_Create(t *testing.T) {
 }
 
 func TestClient_Get(t *testing.T) {
-	ts := testServer(t, `{"method":"get","params":[{"url":"http://example.com/url"},"site"]}`,
+	ts := testServer(t, `{"jsonrpc":"2.0","method":"get","params":[{"url":"http://example.com/url"},"site"],"id":1}`,
 		`{"result":{"id":"123","pid":"","text":"msg","delete":true}}`)
 	defer ts.Close()
 	c := Client{API: ts.URL, Client: http.Client{}}
@@ -41,7 +52,7 @@ func TestClient_Get(t *testing.T) {
 }
 
 func TestClient_GetWithErrorResult(t *testing.T) {
-	ts := testServer(t, `{"method":"get","params":[{"url":"http://example.com/url"},"site"]}`, `{"error":"failed"}`)
+	ts := testServer(t, `{"jsonrpc":"2.0","method":"get","params":[{"url":"http://example.com/url"},"site"],"id":1}`, `{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"failed"}}`)
 	defer ts.Close()
 	c := Client{API: ts.URL, Client: http.Client{}}
 
@@ -50,7 +61,7 @@ func TestClient_GetWithErrorResult(t *testing.T) {
 }
 
 func TestClient_GetWithErrorDecode(t *testing.T) {
-	ts := testServer(t, `{"method":"get","params":[{"url":"http://example.com/url"},"site"]}`, ``)
+	ts := testServer(t, `{"jsonrpc":"2.0","method":"get","params":[{"url":"http://example.com/url"},"site"],"id":1}`, ``)
 	defer ts.Close()
 	c := Client{API: ts.URL, Client: http.Client{}}
 
@@ -81,7 +92,7 @@ func TestClient_FailedStatus(t *testing.T) {
 }
 
 func TestClient_Update(t *testing.T) {
-	ts := testServer(t, `{"method":"update","params":[{"url":"http://example.com/url"},{"id":"123","pid":"","text":"msg","user":{"name":"","id":"","picture":"","admin":false},"locator":{"site":"site123","url":"http://example.com/url"},"score":0,"vote":0,"time":"0001-01-01T00:00:00Z"}]}`, `{}`)
+	ts := testServer(t, `{"jsonrpc":"2.0","method":"update","params":[{"url":"http://example.com/url"},{"id":"123","pid":"","text":"msg","user":{"name":"","id":"","picture":"","admin":false},"locator":{"site":"site123","url":"http://example.com/url"},"score":0,"vote":0,"time":"0001-01-01T00:00:00Z"}],"id":1}`, `{}`)
 	defer ts.Close()
 	c := Client{API: ts.URL, Client: http.Client{}}
 
@@ -92,7 +103,7 @@ func TestClient_Update(t *testing.T) {
 }
 
 func TestClient_Find(t *testing.T) {
-	ts := testServer(t, `{"method":"find","params":[{"locator":{"url":"http://example.com/url"},"sort":"-time","since":"0001-01-01T00:00:00Z","limit":10}]}`, `{"result":[{"text":"1"},{"text":"2"}]}`)
+	ts := testServer(t, `{"jsonrpc":"2.0","method":"find","params":[{"locator":{"url":"http://example.com/url"},"sort":"-time","since":"0001-01-01T00:00:00Z","limit":10}],"id":1}`, `{"result":[{"text":"1"},{"text":"2"}]}`)
 	defer ts.Close()
 	c := Client{API: ts.URL, Client: http.Client{}}
 
@@ -102,7 +113,7 @@ func TestClient_Find(t *testing.T) {
 }
 
 func TestClient_Info(t *testing.T) {
-	ts := testServer(t, `{"method":"info","params":[{"locator":{"url":"http://example.com/url"},"limit":10,"skip":5,"ro_age":10}]}`, `{"result":[{"url":"u1","count":22},{"url":"u2","count":33}]}`)
+	ts := testServer(t, `{"jsonrpc":"2.0","method":"info","params":[{"locator":{"url":"http://example.com/url"},"limit":10,"skip":5,"ro_age":10}],"id":1}`, `{"result":[{"url":"u1","count":22},{"url":"u2","count":33}]}`)
 	defer ts.Close()
 	c := Client{API: ts.URL, Client: http.Client{}}
 
@@ -113,7 +124,7 @@ func TestClient_Info(t *testing.T) {
 }
 
 func TestClient_Flag(t *testing.T) {
-	ts := testServer(t, `{"method":"flag","params":[{"flag":"verified","locator":{"url":"http://example.com/url"}}]}`,
+	ts := testServer(t, `{"jsonrpc":"2.0","method":"flag","params":[{"flag":"verified","locator":{"url":"http://example.com/url"}}],"id":1}`,
 		`{"result":false}`)
 	defer ts.Close()
 	c := Client{API: ts.URL, Client: http.Client{}}
@@ -124,7 +135,7 @@ func TestClient_Flag(t *testing.T) {
 }
 
 func TestClient_ListFlag(t *testing.T) {
-	ts := testServer(t, `{"method":"list_flags","params":["site_id","blocked"]}`, `{"result":[{"ID":"id1"},{"ID":"id2"}]}`)
+	ts := testServer(t, `{"jsonrpc":"2.0","method":"list_flags","params":["site_id","blocked"],"id":1}`, `{"result":[{"ID":"id1"},{"ID":"id2"}]}`)
 	defer ts.Close()
 	c := Client{API: ts.URL, Client: http.Client{}}
 	res, err := c.ListFlags("site_id", engine.Blocked)
@@ -133,7 +144,7 @@ func TestClient_ListFlag(t *testing.T) {
 }
 
 func TestClient_Count(t *testing.T) {
-	ts := testServer(t, `{"method":"count","params":[{"locator":{"url":"http://example.com/url"},"since":"0001-01-01T00:00:00Z"}]}`,
+	ts := testServer(t, `{"jsonrpc":"2.0","method":"count","params":[{"locator":{"url":"http://example.com/url"},"since":"0001-01-01T00:00:00Z"}],"id":1}`,
 		`{"result":11}`)
 	defer ts.Close()
 	c := Client{API: ts.URL, Client: http.Client{}}
@@ -144,7 +155,7 @@ func TestClient_Count(t *testing.T) {
 }
 
 func TestClient_Delete(t *testing.T) {
-	ts := testServer(t, `{"method":"delete","params":[{"locator":{"url":"http://example.com/url"},"del_mode":0}]}`, `{}`)
+	ts := testServer(t, `{"jsonrpc":"2.0","method":"delete","params":[{"locator":{"url":"http://example.com/url"},"del_mode":0}],"id":1}`, `{}`)
 	defer ts.Close()
 	c := Client{API: ts.URL, Client: http.Client{}}
 
@@ -153,13 +164,348 @@ func TestClient_Delete(t *testing.T) {
 }
 
 func TestClient_Close(t *testing.T) {
-	ts := testServer(t, `{"method":"close","params":null}`, `{}`)
+	ts := testServer(t, `{"jsonrpc":"2.0","method":"close","params":null,"id":1}`, `{}`)
 	defer ts.Close()
 	c := Client{API: ts.URL, Client: http.Client{}}
 	err := c.Close()
 	assert.NoError(t, err)
 }
 
+func TestClient_GetCtxCanceledMidFlight(t *testing.T) {
+	started := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(time.Second)
+		fmt.Fprintf(w, `{"result":{"id":"123"}}`)
+	}))
+	defer ts.Close()
+	c := Client{API: ts.URL, Client: http.Client{}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.GetCtx(ctx, store.Locator{URL: "http://example.com/url"}, "site")
+		done <- err
+	}()
+
+	<-started
+	cancel()
+
+	err := <-done
+	require.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "remote call failed for get:"))
+}
+
+func TestClient_GetCtxDeadlineExpired(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		fmt.Fprintf(w, `{"result":{"id":"123"}}`)
+	}))
+	defer ts.Close()
+	c := Client{API: ts.URL, Client: http.Client{}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+
+	_, err := c.GetCtx(ctx, store.Locator{URL: "http://example.com/url"}, "site")
+	require.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "remote call failed for get:"))
+}
+
+func TestClient_LegacyGet(t *testing.T) {
+	ts := testServer(t, `{"method":"get","params":[{"url":"http://example.com/url"},"site"]}`,
+		`{"result":{"id":"123","pid":"","text":"msg","delete":true}}`)
+	defer ts.Close()
+	c := Client{API: ts.URL, Client: http.Client{}, Legacy: true}
+
+	res, err := c.Get(store.Locator{URL: "http://example.com/url"}, "site")
+	assert.NoError(t, err)
+	assert.Equal(t, store.Comment{ID: "123", Text: "msg", Deleted: true}, res)
+}
+
+func TestClient_LegacyGetWithErrorResult(t *testing.T) {
+	ts := testServer(t, `{"method":"get","params":[{"url":"http://example.com/url"},"site"]}`, `{"error":"failed"}`)
+	defer ts.Close()
+	c := Client{API: ts.URL, Client: http.Client{}, Legacy: true}
+
+	_, err := c.Get(store.Locator{URL: "http://example.com/url"}, "site")
+	assert.EqualError(t, err, "failed")
+}
+
+func TestClient_Batch(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.Equal(t, `[{"jsonrpc":"2.0","method":"find","params":[{"locator":{"url":"http://example.com/url"},"since":"0001-01-01T00:00:00Z"}],"id":1},{"jsonrpc":"2.0","method":"count","params":[{"locator":{"url":"http://example.com/url"},"since":"0001-01-01T00:00:00Z"}],"id":2}]`,
+			string(body))
+		fmt.Fprintf(w, `[{"jsonrpc":"2.0","id":2,"result":11},{"jsonrpc":"2.0","id":1,"result":[{"text":"1"}]}]`)
+	}))
+	defer ts.Close()
+	c := Client{API: ts.URL, Client: http.Client{}}
+
+	loc := store.Locator{URL: "http://example.com/url"}
+	res, err := c.Batch(context.Background(), []Call{
+		{Method: "find", Params: []interface{}{engine.FindRequest{Locator: loc}}},
+		{Method: "count", Params: []interface{}{engine.FindRequest{Locator: loc}}},
+	})
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+
+	var comments []store.Comment
+	require.NoError(t, json.Unmarshal(res[0].Result, &comments))
+	assert.Equal(t, []store.Comment{{Text: "1"}}, comments)
+
+	var count int
+	require.NoError(t, json.Unmarshal(res[1].Result, &count))
+	assert.Equal(t, 11, count)
+}
+
+func TestClient_BatchNotSupportedInLegacyMode(t *testing.T) {
+	c := Client{API: "http://127.0.0.1", Client: http.Client{}, Legacy: true}
+	_, err := c.Batch(context.Background(), []Call{{Method: "find"}})
+	assert.EqualError(t, err, "batch calls are not supported in legacy mode")
+}
+
+func TestClient_BatchRejectsWriteMethods(t *testing.T) {
+	c := Client{API: "http://127.0.0.1", Client: http.Client{}}
+	_, err := c.Batch(context.Background(), []Call{{Method: "find"}, {Method: "create"}})
+	assert.EqualError(t, err, `batch calls do not support write method "create"`)
+}
+
+func TestClient_BearerAuth(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer tok123", r.Header.Get("Authorization"))
+		fmt.Fprintf(w, `{}`)
+	}))
+	defer ts.Close()
+
+	c := Client{API: ts.URL, Auth: BearerAuth{Token: func() (string, error) { return "tok123", nil }}}
+	assert.NoError(t, c.Close())
+}
+
+func TestClient_BasicAuth(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "user", user)
+		assert.Equal(t, "pwd", pass)
+		fmt.Fprintf(w, `{}`)
+	}))
+	defer ts.Close()
+
+	c := Client{API: ts.URL, Auth: BasicAuth{User: "user", Password: "pwd"}}
+	assert.NoError(t, c.Close())
+}
+
+func TestClient_HMACAuthOverTLS(t *testing.T) {
+	secret := []byte("s3cr3t")
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		tsHeader := r.Header.Get(hmacTimestampHeader)
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(body)             // nolint errcheck
+		mac.Write([]byte(tsHeader)) // nolint errcheck
+		assert.Equal(t, "key1:"+hex.EncodeToString(mac.Sum(nil)), r.Header.Get(hmacSignatureHeader))
+
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":1,"result":{"id":"123"}}`)
+	}))
+	defer ts.Close()
+
+	c := Client{API: ts.URL, Auth: HMACAuth{KeyID: "key1", Secret: secret}}
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+	require.NoError(t, ioutil.WriteFile(caFile, caPEM, 0o600))
+	require.NoError(t, c.SetTLSConfig(TLSConfig{CAFile: caFile}))
+
+	res, err := c.Get(store.Locator{URL: "http://example.com/url"}, "site")
+	assert.NoError(t, err)
+	assert.Equal(t, store.Comment{ID: "123"}, res)
+}
+
+func TestClient_SetTLSConfigPreservesDefaultTransport(t *testing.T) {
+	c := Client{API: "http://127.0.0.1"}
+	require.NoError(t, c.SetTLSConfig(TLSConfig{}))
+
+	transport, ok := c.Client.Transport.(*http.Transport)
+	require.True(t, ok)
+	require.NotNil(t, transport.Proxy, "SetTLSConfig must not drop Proxy: http.ProxyFromEnvironment")
+}
+
+func TestAuthMiddleware_BearerVerifier(t *testing.T) {
+	verifier := BearerVerifier{Valid: func(token string) bool { return token == "tok123" }}
+	handler := AuthMiddleware(verifier, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{}`)
+	}))
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	ok := Client{API: ts.URL, Auth: BearerAuth{Token: func() (string, error) { return "tok123", nil }}}
+	assert.NoError(t, ok.Close())
+
+	bad := Client{API: ts.URL, Auth: BearerAuth{Token: func() (string, error) { return "wrong", nil }}}
+	err := bad.Close()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "401")
+
+	noAuth := Client{API: ts.URL}
+	err = noAuth.Close()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "401")
+}
+
+func TestAuthMiddleware_HMACVerifier(t *testing.T) {
+	secret := []byte("s3cr3t")
+	verifier := HMACVerifier{Secret: func(keyID string) ([]byte, bool) {
+		if keyID != "key1" {
+			return nil, false
+		}
+		return secret, true
+	}}
+	handler := AuthMiddleware(verifier, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{}`)
+	}))
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	ok := Client{API: ts.URL, Auth: HMACAuth{KeyID: "key1", Secret: secret}}
+	assert.NoError(t, ok.Close())
+
+	wrongSecret := Client{API: ts.URL, Auth: HMACAuth{KeyID: "key1", Secret: []byte("nope")}}
+	err := wrongSecret.Close()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "401")
+
+	unknownKey := Client{API: ts.URL, Auth: HMACAuth{KeyID: "key2", Secret: secret}}
+	err = unknownKey.Close()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "401")
+
+	noAuth := Client{API: ts.URL}
+	err = noAuth.Close()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "401")
+}
+
+func TestHMACVerifier_RejectsStaleTimestamp(t *testing.T) {
+	secret := []byte("s3cr3t")
+	verifier := HMACVerifier{Secret: func(string) ([]byte, bool) { return secret, true }, MaxSkew: time.Minute}
+
+	body := []byte(`{}`)
+	ts := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)       // nolint errcheck
+	mac.Write([]byte(ts)) // nolint errcheck
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	req.Header.Set(hmacTimestampHeader, ts)
+	req.Header.Set(hmacSignatureHeader, "key1:"+hex.EncodeToString(mac.Sum(nil)))
+
+	err := verifier.Verify(req, body)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "skew")
+}
+
+func TestClient_RetrySucceedsAfterTransientFailures(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":1,"result":{"id":"123"}}`)
+	}))
+	defer ts.Close()
+
+	c := Client{API: ts.URL, Retry: RetryConfig{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}}
+
+	res, err := c.Get(store.Locator{URL: "http://example.com/url"}, "site")
+	assert.NoError(t, err)
+	assert.Equal(t, store.Comment{ID: "123"}, res)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestClient_RetryHonorsRetryAfter(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":1,"result":{"id":"123"}}`)
+	}))
+	defer ts.Close()
+
+	// backoff alone would wait at most 1ms, so a wait of a second can only come from Retry-After
+	c := Client{API: ts.URL, Retry: RetryConfig{BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}}
+	start := time.Now()
+	res, err := c.Get(store.Locator{URL: "http://example.com/url"}, "site")
+	assert.NoError(t, err)
+	assert.Equal(t, store.Comment{ID: "123"}, res)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	assert.True(t, time.Since(start) >= time.Second, "retry should wait for Retry-After")
+}
+
+func TestClient_RetryExhausted(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	c := Client{API: ts.URL, Retry: RetryConfig{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}}
+
+	_, err := c.Get(store.Locator{URL: "http://example.com/url"}, "site")
+	assert.EqualError(t, err, "bad status 503 for get")
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestClient_WriteDoesNotRetryOnServerError(t *testing.T) {
+	var calls int32
+	var firstKey string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		firstKey = r.Header.Get("Idempotency-Key")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	c := Client{API: ts.URL, Retry: RetryConfig{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}}
+
+	_, err := c.Create(store.Comment{ID: "123"})
+	assert.EqualError(t, err, "bad status 503 for create")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	assert.NotEmpty(t, firstKey)
+}
+
+// roundTripperFunc adapts a function to http.RoundTripper, so tests can stub transport-level
+// behavior (e.g. a connection failure) without a real listener.
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestClient_WriteRetriesOnConnectionFailure(t *testing.T) {
+	var calls int32
+	transport := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, &net.OpError{Op: "dial", Err: fmt.Errorf("connection refused")}
+	})
+
+	c := Client{
+		API:    "http://127.0.0.1",
+		Client: http.Client{Transport: transport},
+		Retry:  RetryConfig{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond},
+	}
+	_, err := c.Create(store.Comment{ID: "123"})
+	assert.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "remote call failed for create:"))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls), "expected one initial attempt plus one retry")
+}
+
 func testServer(t *testing.T, req, resp string) *httptest.Server {
 	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		body, err := ioutil.ReadAll(r.Body)
@@ -168,4 +514,4 @@ func testServer(t *testing.T, req, resp string) *httptest.Server {
 		t.Logf("req: %s", string(body))
 		fmt.Fprintf(w, resp)
 	}))
-}
\ No newline at end of file
+}
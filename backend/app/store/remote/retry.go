@@ -0,0 +1,232 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	mrand "math/rand"
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultMaxAttempts = 3
+	defaultRetryBase   = 100 * time.Millisecond
+	defaultRetryCap    = time.Second
+)
+
+// RetryConfig controls the retry/backoff behavior for transient remote-engine failures.
+// A zero value uses the defaults: 3 attempts, 100ms base backoff doubling up to a 1s cap,
+// with full jitter.
+type RetryConfig struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+func (c RetryConfig) withDefaults() RetryConfig {
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = defaultMaxAttempts
+	}
+	if c.BaseDelay <= 0 {
+		c.BaseDelay = defaultRetryBase
+	}
+	if c.MaxDelay <= 0 {
+		c.MaxDelay = defaultRetryCap
+	}
+	return c
+}
+
+// idempotencyKeyHeader carries a UUID identifying a logical write call so the server can
+// dedupe it if a retried attempt reaches it twice.
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// doWithRetry posts body to r.API, retrying transient failures with exponential backoff and
+// full jitter (sleep = rand(0, min(cap, base*2^attempt))) up to Retry.MaxAttempts. ctx
+// cancellation short-circuits the backoff wait. On success the returned response has status
+// 200; the caller owns closing its body.
+//
+// isWrite marks Create/Update/Delete/Flag: once such a request reaches the server we can no
+// longer tell whether it was applied, so it is retried only when the failure happened before
+// the server ever saw it (connection refused/reset). An Idempotency-Key header, stable across
+// attempts of the same logical call, is sent so a server that does retry/dedupe can do so safely.
+func (r *Client) doWithRetry(ctx context.Context, method string, body []byte, isWrite bool) (*http.Response, error) {
+	retry := r.Retry.withDefaults()
+
+	var idemKey string
+	if isWrite {
+		key, err := newIdempotencyKey()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to generate idempotency key for %s", method)
+		}
+		idemKey = key
+	}
+
+	var lastErr error
+	var wait time.Duration
+
+	for attempt := 0; attempt < retry.MaxAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, errors.Wrapf(ctx.Err(), "remote call canceled for %s", method)
+			case <-time.After(wait):
+			}
+		}
+
+		httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, r.API, bytes.NewReader(body))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to make request for %s", method)
+		}
+		if idemKey != "" {
+			httpReq.Header.Set(idempotencyKeyHeader, idemKey)
+		}
+		if err = r.sign(httpReq, body); err != nil {
+			return nil, errors.Wrapf(err, "failed to sign request for %s", method)
+		}
+
+		// for writes we need to know whether the request was actually put on the wire, so a
+		// failure while waiting for (or reading) the response isn't mistaken for one that
+		// never reached the server.
+		var tracker *sendTracker
+		if isWrite {
+			tracker = &sendTracker{}
+			httpReq = withSendTracker(httpReq, tracker)
+		}
+
+		resp, doErr := r.Client.Do(httpReq)
+		canRetry, retryAfter := retryDecision(isWrite, tracker.sent(), doErr, resp)
+		isLastAttempt := attempt == retry.MaxAttempts-1
+
+		if doErr != nil {
+			lastErr = errors.Wrapf(doErr, "remote call failed for %s", method)
+			if !canRetry || isLastAttempt || ctx.Err() != nil {
+				return nil, lastErr
+			}
+			wait = nextDelay(retry, attempt, retryAfter)
+			continue
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			lastErr = errors.Errorf("bad status %d for %s", resp.StatusCode, method)
+			resp.Body.Close() // nolint gas
+			if !canRetry || isLastAttempt {
+				return nil, lastErr
+			}
+			wait = nextDelay(retry, attempt, retryAfter)
+			continue
+		}
+
+		return resp, nil
+	}
+	return nil, lastErr
+}
+
+func nextDelay(retry RetryConfig, attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return retryAfter
+	}
+	return backoffDelay(retry.BaseDelay, retry.MaxDelay, attempt)
+}
+
+// retryDecision reports whether a failed attempt should be retried and, for 429 responses,
+// how long the server asked us to wait via Retry-After. sent reports whether the request was
+// actually written to the wire (per sendTracker), which for writes distinguishes a failure
+// the server never saw from one that hit after it already received (and maybe applied) the call.
+func retryDecision(isWrite, sent bool, err error, resp *http.Response) (retry bool, retryAfter time.Duration) {
+	if err != nil {
+		if isWrite && sent {
+			// the request reached the server before the failure (e.g. a timeout waiting for
+			// or reading the response); it may already have been applied, so resending it
+			// without a dedupe-aware server risks a duplicate.
+			return false, 0
+		}
+		// failed before the request was sent (e.g. connection refused/reset): the server
+		// never saw it, so it's safe to retry even a write.
+		return true, 0
+	}
+	if isWrite {
+		// the server responded, so the write may already have been applied; it is not
+		// retried, whatever the response's status or headers.
+		return false, 0
+	}
+	switch {
+	case resp.StatusCode == http.StatusRequestTimeout:
+		return true, 0
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return true, retryAfterDuration(resp)
+	case resp.StatusCode >= http.StatusInternalServerError && resp.StatusCode != http.StatusNotImplemented:
+		return true, 0
+	default:
+		return false, 0
+	}
+}
+
+// retryAfterDuration parses a Retry-After header (seconds or HTTP date), returning 0 if
+// absent or unparsable so the caller falls back to its own backoff.
+func retryAfterDuration(resp *http.Response) time.Duration {
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if when, err := http.ParseTime(v); err == nil {
+		if d := time.Until(when); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// backoffDelay computes a full-jitter exponential backoff: rand(0, min(maxDelay, base*2^attempt)).
+func backoffDelay(base, maxDelay time.Duration, attempt int) time.Duration {
+	upper := base * time.Duration(int64(1)<<uint(attempt))
+	if upper > maxDelay || upper <= 0 {
+		upper = maxDelay
+	}
+	return time.Duration(mrand.Int63n(int64(upper) + 1)) // nolint gosec
+}
+
+// sendTracker records whether a request was fully written to the wire, via an
+// httptrace.ClientTrace.WroteRequest hook. A nil *sendTracker reports not sent.
+type sendTracker struct {
+	wrote int32
+}
+
+func (t *sendTracker) mark(err error) {
+	if err == nil {
+		atomic.StoreInt32(&t.wrote, 1)
+	}
+}
+
+func (t *sendTracker) sent() bool {
+	return t != nil && atomic.LoadInt32(&t.wrote) == 1
+}
+
+// withSendTracker attaches a trace to req that has t record whether the request body was
+// fully written before any failure.
+func withSendTracker(req *http.Request, t *sendTracker) *http.Request {
+	trace := &httptrace.ClientTrace{WroteRequest: func(info httptrace.WroteRequestInfo) { t.mark(info.Err) }}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+}
+
+// newIdempotencyKey generates a random UUID (v4) identifying one logical write call across
+// all of its retry attempts.
+func newIdempotencyKey() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40 // version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // variant 10
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}